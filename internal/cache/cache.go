@@ -0,0 +1,88 @@
+// Package cache provides a content-addressable, on-disk cache of extracted
+// olm.bundle.object data, keyed by bundle image digest. It lets repeated
+// runs over large catalogs skip pulling bundle images whose digest has
+// already been inlined.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry is the cached data for a single bundle image digest.
+type Entry struct {
+	MediaType string            `json:"mediaType"`
+	Objects   []json.RawMessage `json:"objects"`
+}
+
+// Cache is rooted at a directory containing one "<algorithm>/<hex>/objects.json"
+// file per cached digest, e.g. "<dir>/sha256/<hex>/objects.json".
+type Cache struct {
+	dir string
+}
+
+// New returns a Cache rooted at dir, creating dir if it doesn't already exist.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Get returns the cached Entry for digest, if present.
+func (c *Cache) Get(digest string) (Entry, bool, error) {
+	var e Entry
+	path, err := c.objectsPath(digest)
+	if err != nil {
+		return e, false, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return e, false, nil
+		}
+		return e, false, err
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, false, err
+	}
+	return e, true, nil
+}
+
+// Put stores e as the cached Entry for digest.
+func (c *Cache) Put(digest string, e Entry) error {
+	path, err := c.objectsPath(digest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// objectsPath returns "<dir>/<algorithm>/<hex>/objects.json" for digest,
+// which must be of the form "<algorithm>:<hex>" (e.g. a v1.Hash string).
+func (c *Cache) objectsPath(digest string) (string, error) {
+	algo, hex, ok := splitDigest(digest)
+	if !ok {
+		return "", fmt.Errorf("invalid digest %q: must be of the form <algorithm>:<hex>", digest)
+	}
+	return filepath.Join(c.dir, algo, hex, "objects.json"), nil
+}
+
+func splitDigest(digest string) (algo, hex string, ok bool) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
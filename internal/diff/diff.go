@@ -0,0 +1,241 @@
+// Package diff renders a minimal unified diff between two text documents,
+// used by the tool's dry-run mode to preview changes before they're written
+// to disk.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change, matching
+// the default of GNU diff -u.
+const context = 3
+
+// opKind identifies how a line differs between a and b.
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line of the edit script between a and b, along with its
+// 0-based index in whichever of a/b it came from.
+type op struct {
+	kind opKind
+	line string
+	aIdx int
+	bIdx int
+}
+
+// Unified returns a unified diff between a and b, labeled with aName and
+// bName. It returns "" if a and b are identical.
+func Unified(aName, bName, a, b string) string {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	if equalLines(aLines, bLines) {
+		return ""
+	}
+
+	ops := editScript(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", aName)
+	fmt.Fprintf(&sb, "+++ %s\n", bName)
+	for _, h := range hunks(ops) {
+		h.writeTo(&sb)
+	}
+	return sb.String()
+}
+
+// editScript walks a and b alongside their longest common subsequence and
+// returns the resulting sequence of equal/delete/insert line operations.
+func editScript(aLines, bLines []string) []op {
+	lcs := longestCommonSubsequence(aLines, bLines)
+
+	// Every op carries both aIdx and bIdx: the 0-based a/b line position it
+	// sits at, whether or not that side actually contributed a line. This
+	// lets a hunk's boundary ops alone determine its @@ header, including
+	// the "pure insert/delete" case where one side contributes 0 lines.
+	var ops []op
+	ai, bi, li := 0, 0, 0
+	for ai < len(aLines) || bi < len(bLines) {
+		switch {
+		case li < len(lcs) && ai < len(aLines) && bi < len(bLines) && aLines[ai] == lcs[li] && bLines[bi] == lcs[li]:
+			ops = append(ops, op{kind: opEqual, line: aLines[ai], aIdx: ai, bIdx: bi})
+			ai++
+			bi++
+			li++
+		case ai < len(aLines) && (li >= len(lcs) || aLines[ai] != lcs[li]):
+			ops = append(ops, op{kind: opDelete, line: aLines[ai], aIdx: ai, bIdx: bi})
+			ai++
+		case bi < len(bLines) && (li >= len(lcs) || bLines[bi] != lcs[li]):
+			ops = append(ops, op{kind: opInsert, line: bLines[bi], aIdx: ai, bIdx: bi})
+			bi++
+		default:
+			// Shouldn't happen, but avoid looping forever.
+			ai++
+			bi++
+		}
+	}
+	return ops
+}
+
+// hunk is a contiguous run of ops, bracketed by up to context lines of
+// unchanged context on either side, rendered with a @@ -l,s +l,s @@ header.
+type hunk struct {
+	aStart, aCount int
+	bStart, bCount int
+	ops            []op
+}
+
+// hunks groups ops into hunks the way GNU diff -u does: runs of changes
+// separated by more than 2*context unchanged lines become separate hunks,
+// each bordered by up to context lines of surrounding, unchanged context.
+func hunks(ops []op) []hunk {
+	var changed []int
+	for i, o := range ops {
+		if o.kind != opEqual {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var result []hunk
+	start := changed[0]
+	end := changed[0]
+	for _, i := range changed[1:] {
+		if i-end-1 <= 2*context {
+			end = i
+			continue
+		}
+		result = append(result, newHunk(ops, start, end))
+		start = i
+		end = i
+	}
+	result = append(result, newHunk(ops, start, end))
+	return result
+}
+
+// newHunk builds a hunk covering ops[start:end+1] plus up to context lines of
+// unchanged padding on either side.
+func newHunk(ops []op, start, end int) hunk {
+	lo := start - context
+	if lo < 0 {
+		lo = 0
+	}
+	hi := end + context
+	if hi > len(ops)-1 {
+		hi = len(ops) - 1
+	}
+
+	h := hunk{ops: ops[lo : hi+1]}
+	for _, o := range h.ops {
+		switch o.kind {
+		case opEqual:
+			h.aCount++
+			h.bCount++
+		case opDelete:
+			h.aCount++
+		case opInsert:
+			h.bCount++
+		}
+	}
+	first := h.ops[0]
+	if h.aCount == 0 {
+		h.aStart = first.aIdx
+	} else {
+		h.aStart = first.aIdx + 1
+	}
+	if h.bCount == 0 {
+		h.bStart = first.bIdx
+	} else {
+		h.bStart = first.bIdx + 1
+	}
+	return h
+}
+
+func (h hunk) writeTo(sb *strings.Builder) {
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", rangeStr(h.aStart, h.aCount), rangeStr(h.bStart, h.bCount))
+	for _, o := range h.ops {
+		switch o.kind {
+		case opEqual:
+			sb.WriteString(" " + o.line + "\n")
+		case opDelete:
+			sb.WriteString("-" + o.line + "\n")
+		case opInsert:
+			sb.WriteString("+" + o.line + "\n")
+		}
+	}
+}
+
+// rangeStr formats a hunk's line range the way GNU diff -u does: a bare line
+// number when the range is exactly one line, and "start,count" otherwise
+// (including "start,0" for a pure insertion/deletion point).
+func rangeStr(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// longestCommonSubsequence returns the longest common subsequence of lines
+// between a and b, computed via the standard O(n*m) dynamic program. This is
+// fine for declarative config files, which are not expected to be huge.
+func longestCommonSubsequence(a, b []string) []string {
+	n, m := len(a), len(b)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var lcs []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lcs = append(lcs, a[i])
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return lcs
+}
@@ -0,0 +1,77 @@
+package diff
+
+import "testing"
+
+func TestUnifiedNoChange(t *testing.T) {
+	a := "line1\nline2\n"
+	if got := Unified("a", "b", a, a); got != "" {
+		t.Errorf("Unified with identical input = %q, want \"\"", got)
+	}
+}
+
+func TestUnifiedSingleLineChange(t *testing.T) {
+	a := "line1\nline2\nline3\n"
+	b := "line1\nCHANGED\nline3\n"
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" line1\n" +
+		"-line2\n" +
+		"+CHANGED\n" +
+		" line3\n"
+	if got := Unified("a", "b", a, b); got != want {
+		t.Errorf("Unified:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedSplitsDistantChangesIntoSeparateHunks(t *testing.T) {
+	// Two single-line changes far enough apart (more than 2*context lines
+	// of unchanged content between them) must produce two @@ hunks, each
+	// with only its own surrounding context, not one hunk spanning the
+	// whole file.
+	lines := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		lines = append(lines, "ctx")
+	}
+	aLines := append([]string(nil), lines...)
+	bLines := append([]string(nil), lines...)
+	aLines[0] = "first-old"
+	bLines[0] = "first-new"
+	aLines[19] = "last-old"
+	bLines[19] = "last-new"
+
+	a := joinWithNewline(aLines)
+	b := joinWithNewline(bLines)
+
+	got := Unified("a", "b", a, b)
+	hunkCount := 0
+	for _, line := range splitLines(got) {
+		if len(line) >= 2 && line[:2] == "@@" {
+			hunkCount++
+		}
+	}
+	if hunkCount != 2 {
+		t.Errorf("got %d hunks, want 2:\n%s", hunkCount, got)
+	}
+}
+
+func TestUnifiedPureInsertion(t *testing.T) {
+	a := "line1\n"
+	b := "line1\nline2\n"
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -1 +1,2 @@\n" +
+		" line1\n" +
+		"+line2\n"
+	if got := Unified("a", "b", a, b); got != want {
+		t.Errorf("Unified:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func joinWithNewline(lines []string) string {
+	var s string
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package puller
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	log "github.com/sirupsen/logrus"
+)
+
+// containerdPuller pulls bundle images using operator-registry's
+// containerd-backed registry, which keeps its own content store rooted at a
+// writable directory (e.g. /var/lib/containerd in production).
+type containerdPuller struct {
+	registry *containerdregistry.Registry
+}
+
+// NewContainerd returns a BundlePuller backed by containerd's image and
+// content store machinery. This is the original, default puller backend.
+func NewContainerd() (BundlePuller, error) {
+	reg, err := containerdregistry.NewRegistry(containerdregistry.WithLog(noopLogger()))
+	if err != nil {
+		return nil, err
+	}
+	return &containerdPuller{registry: reg}, nil
+}
+
+func (p *containerdPuller) Pull(ctx context.Context, ref string) (fs.FS, error) {
+	imgRef := image.SimpleReference(ref)
+	if err := p.registry.Pull(ctx, imgRef); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "declcfg-inline-bundles-")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.registry.Unpack(ctx, imgRef, tmpDir); err != nil {
+		return nil, err
+	}
+	return DirFS{FS: os.DirFS(tmpDir), Dir: tmpDir}, nil
+}
+
+func (p *containerdPuller) Close() error {
+	return p.registry.Destroy()
+}
+
+func noopLogger() *log.Entry {
+	l := log.New()
+	l.Out = ioutil.Discard
+	return log.NewEntry(l)
+}
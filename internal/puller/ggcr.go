@@ -0,0 +1,108 @@
+package puller
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ggcrPuller pulls and flattens bundle images using go-containerregistry,
+// without requiring a containerd content store. This makes it usable in
+// restricted CI environments, and it supports multi-arch platform selection
+// and keychain-based registry auth (docker config, k8s pull secrets, and
+// cloud registry credential helpers).
+type ggcrPuller struct {
+	platform *v1.Platform
+	keychain authn.Keychain
+}
+
+// GGCROption configures a go-containerregistry-backed BundlePuller.
+type GGCROption func(*ggcrPuller)
+
+// WithPlatform selects a specific platform from a multi-arch bundle image.
+func WithPlatform(platform v1.Platform) GGCROption {
+	return func(p *ggcrPuller) { p.platform = &platform }
+}
+
+// WithKeychain overrides the default keychain used for registry auth.
+func WithKeychain(keychain authn.Keychain) GGCROption {
+	return func(p *ggcrPuller) { p.keychain = keychain }
+}
+
+// NewGGCR returns a BundlePuller backed by go-containerregistry.
+func NewGGCR(opts ...GGCROption) BundlePuller {
+	p := &ggcrPuller{keychain: authn.DefaultKeychain}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *ggcrPuller) Pull(ctx context.Context, ref string) (fs.FS, error) {
+	nref, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("parse image reference %q: %v", ref, err)
+	}
+
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(p.keychain)}
+	if p.platform != nil {
+		opts = append(opts, remote.WithPlatform(*p.platform))
+	}
+
+	img, err := remote.Image(nref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("pull image %q: %v", ref, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "declcfg-inline-bundles-")
+	if err != nil {
+		return nil, err
+	}
+	if err := extractImage(img, tmpDir); err != nil {
+		return nil, err
+	}
+	return DirFS{FS: os.DirFS(tmpDir), Dir: tmpDir}, nil
+}
+
+func (p *ggcrPuller) Close() error {
+	return nil
+}
+
+// Labels returns ref's OCI image config labels, resolved directly from the
+// registry without extracting the image's layers.
+func (p *ggcrPuller) Labels(ctx context.Context, ref string) (map[string]string, error) {
+	nref, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("parse image reference %q: %v", ref, err)
+	}
+
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(p.keychain)}
+	if p.platform != nil {
+		opts = append(opts, remote.WithPlatform(*p.platform))
+	}
+
+	img, err := remote.Image(nref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("pull image %q: %v", ref, err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("read image config %q: %v", ref, err)
+	}
+	return cfg.Config.Labels, nil
+}
+
+// extractImage flattens img's layers into a single filesystem tree rooted at
+// dir, the same shape that containerd's Unpack produces.
+func extractImage(img v1.Image, dir string) error {
+	rc := mutate.Extract(img)
+	defer rc.Close()
+	return untar(rc, dir)
+}
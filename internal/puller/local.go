@@ -0,0 +1,129 @@
+package puller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// localPuller resolves bundle image references against a static map of
+// image reference to local directory path, so that bundles that have never
+// been pushed to a registry can still be inlined.
+type localPuller struct {
+	paths map[string]string
+}
+
+// NewLocal returns a BundlePuller that resolves ref against paths, a map of
+// image reference to local bundle directory.
+func NewLocal(paths map[string]string) BundlePuller {
+	return &localPuller{paths: paths}
+}
+
+func (p *localPuller) Pull(ctx context.Context, ref string) (fs.FS, error) {
+	dir, ok := p.paths[ref]
+	if !ok {
+		return nil, fmt.Errorf("no local path configured for image %q", ref)
+	}
+	dir, err := resolveLocalPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local bundle path %q is not a directory", dir)
+	}
+	return DirFS{FS: os.DirFS(dir), Dir: dir}, nil
+}
+
+func (p *localPuller) Close() error {
+	return nil
+}
+
+// Digest derives a stable cache key from ref itself. Local bundle
+// directories have no registry-assigned content digest, and resolving one
+// would require the network round trip this backend exists to avoid.
+func (p *localPuller) Digest(ctx context.Context, ref string) (string, error) {
+	if _, ok := p.paths[ref]; !ok {
+		return "", fmt.Errorf("no local path configured for image %q", ref)
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// resolveLocalPath accepts either a plain filesystem path or a file://
+// URI for the local side of a --local image=path mapping.
+func resolveLocalPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "file://") {
+		return path, nil
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("parse file URI %q: %v", path, err)
+	}
+	// For a non-triple-slash URI like file://./rel/path or
+	// file://relative/path, url.Parse puts the leading path segment in
+	// Host rather than Path. Recombine them so callers get the full
+	// path instead of silently losing that segment.
+	return u.Host + u.Path, nil
+}
+
+// overridePuller serves images present in a local path map directly from
+// disk, and otherwise delegates to base. This lets --local be combined with
+// any registry-backed puller.
+type overridePuller struct {
+	base  BundlePuller
+	paths map[string]string
+}
+
+// WithLocalOverrides wraps base so that any image reference present in paths
+// is read from the local filesystem instead of being pulled from a registry.
+func WithLocalOverrides(base BundlePuller, paths map[string]string) BundlePuller {
+	if len(paths) == 0 {
+		return base
+	}
+	return &overridePuller{base: base, paths: paths}
+}
+
+func (p *overridePuller) Pull(ctx context.Context, ref string) (fs.FS, error) {
+	if _, ok := p.paths[ref]; ok {
+		return NewLocal(p.paths).Pull(ctx, ref)
+	}
+	return p.base.Pull(ctx, ref)
+}
+
+func (p *overridePuller) Close() error {
+	return p.base.Close()
+}
+
+// Digest resolves ref via the local path map when present, without
+// touching the network, and otherwise delegates to base.
+func (p *overridePuller) Digest(ctx context.Context, ref string) (string, error) {
+	if _, ok := p.paths[ref]; ok {
+		return NewLocal(p.paths).(DigestPuller).Digest(ctx, ref)
+	}
+	if dp, ok := p.base.(DigestPuller); ok {
+		return dp.Digest(ctx, ref)
+	}
+	return "", ErrDigestUnsupported
+}
+
+// Labels resolves ref via the local path map when present, and otherwise
+// delegates to base. Local bundle directories have no image config to
+// source labels from.
+func (p *overridePuller) Labels(ctx context.Context, ref string) (map[string]string, error) {
+	if _, ok := p.paths[ref]; ok {
+		return nil, nil
+	}
+	if lp, ok := p.base.(LabelPuller); ok {
+		return lp.Labels(ctx, ref)
+	}
+	return nil, nil
+}
@@ -0,0 +1,27 @@
+package puller
+
+import "testing"
+
+func TestResolveLocalPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "plain path", path: "/abs/path", want: "/abs/path"},
+		{name: "triple-slash absolute URI", path: "file:///abs/path", want: "/abs/path"},
+		{name: "relative URI with dot segment", path: "file://./rel/path", want: "./rel/path"},
+		{name: "relative URI with no leading dot", path: "file://relative/path", want: "relative/path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLocalPath(tt.path)
+			if err != nil {
+				t.Fatalf("resolveLocalPath(%q): %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveLocalPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package puller
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+// ociLayoutPuller reads bundle images out of a local OCI image layout
+// directory (e.g. one produced by `crane pull --format=oci`), so users can
+// inline bundles that were mirrored offline without a live registry.
+type ociLayoutPuller struct {
+	layoutDir string
+}
+
+// NewOCILayout returns a BundlePuller that resolves image references against
+// the OCI image layout rooted at layoutDir.
+func NewOCILayout(layoutDir string) BundlePuller {
+	return &ociLayoutPuller{layoutDir: layoutDir}
+}
+
+func (p *ociLayoutPuller) Pull(ctx context.Context, ref string) (fs.FS, error) {
+	idx, err := layout.ImageIndexFromPath(p.layoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("load oci layout %q: %v", p.layoutDir, err)
+	}
+
+	img, err := findImageByRef(idx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("find image %q in oci layout %q: %v", ref, p.layoutDir, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "declcfg-inline-bundles-")
+	if err != nil {
+		return nil, err
+	}
+	if err := extractImage(img, tmpDir); err != nil {
+		return nil, err
+	}
+	return DirFS{FS: os.DirFS(tmpDir), Dir: tmpDir}, nil
+}
+
+func (p *ociLayoutPuller) Close() error {
+	return nil
+}
+
+// Digest resolves ref to its content digest by reading the local OCI
+// layout, without any network access.
+func (p *ociLayoutPuller) Digest(ctx context.Context, ref string) (string, error) {
+	idx, err := layout.ImageIndexFromPath(p.layoutDir)
+	if err != nil {
+		return "", fmt.Errorf("load oci layout %q: %v", p.layoutDir, err)
+	}
+	img, err := findImageByRef(idx, ref)
+	if err != nil {
+		return "", fmt.Errorf("find image %q in oci layout %q: %v", ref, p.layoutDir, err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return digest.String(), nil
+}
+
+// Labels returns ref's OCI image config labels, read from the local OCI
+// layout.
+func (p *ociLayoutPuller) Labels(ctx context.Context, ref string) (map[string]string, error) {
+	idx, err := layout.ImageIndexFromPath(p.layoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("load oci layout %q: %v", p.layoutDir, err)
+	}
+	img, err := findImageByRef(idx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("find image %q in oci layout %q: %v", ref, p.layoutDir, err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("read image config %q: %v", ref, err)
+	}
+	return cfg.Config.Labels, nil
+}
+
+// findImageByRef locates the manifest in idx whose digest or
+// "org.opencontainers.image.ref.name" annotation matches ref.
+func findImageByRef(idx v1.ImageIndex, ref string) (v1.Image, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.Digest.String() == ref || desc.Annotations["org.opencontainers.image.ref.name"] == ref {
+			return idx.Image(desc.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no matching image found")
+}
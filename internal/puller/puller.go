@@ -0,0 +1,56 @@
+// Package puller provides pluggable backends for retrieving the unpacked
+// filesystem contents of a bundle image, so that main.go does not need to
+// depend on any one registry client implementation.
+package puller
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// BundlePuller retrieves a bundle image and exposes its unpacked contents.
+type BundlePuller interface {
+	// Pull resolves and retrieves ref, returning a filesystem rooted at the
+	// image's unpacked contents.
+	Pull(ctx context.Context, ref string) (fs.FS, error)
+
+	// Close releases any resources held by the puller.
+	Close() error
+}
+
+// ErrDigestUnsupported is returned by DigestPuller.Digest when a backend has
+// no way to resolve a digest other than a registry round trip, so the
+// caller should fall back to resolving one itself.
+var ErrDigestUnsupported = errors.New("puller backend does not support digest resolution")
+
+// DigestPuller is an optional capability a BundlePuller backend may
+// implement when it can resolve ref to a content digest without a registry
+// round trip (oci-layout, local). Backends that always need one to learn a
+// digest (containerd, ggcr) don't implement it, so callers type-assert for
+// it and fall back to resolving the digest themselves when it's absent.
+type DigestPuller interface {
+	// Digest resolves ref to a content digest, or returns
+	// ErrDigestUnsupported if this backend has no cheaper way to do so than
+	// a registry round trip.
+	Digest(ctx context.Context, ref string) (string, error)
+}
+
+// LabelPuller is an optional capability a BundlePuller backend may
+// implement when it has direct access to the pulled image's config (ggcr,
+// oci-layout). Callers type-assert for it and fall back to other signals,
+// like inspecting the unpacked filesystem, when a backend doesn't
+// implement it (e.g. containerd's unpack output has no config attached).
+type LabelPuller interface {
+	// Labels returns ref's OCI image config labels.
+	Labels(ctx context.Context, ref string) (map[string]string, error)
+}
+
+// DirFS is returned by every backend in this package, since each one
+// materializes a bundle image's contents to a local directory before
+// handing them off to registry.NewImageInput. Callers that need the
+// underlying path (rather than just an fs.FS) can type-assert to DirFS.
+type DirFS struct {
+	fs.FS
+	Dir string
+}
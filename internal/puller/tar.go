@@ -0,0 +1,63 @@
+package puller
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// untar extracts a tar stream into dir, creating directories as needed. It's
+// used to flatten the layers of a go-containerregistry image into the same
+// directory shape that containerd's Unpack produces.
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := sanitizeTarPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		}
+	}
+}
+
+// sanitizeTarPath resolves name against dir and rejects any result that
+// escapes dir, guarding against tar entries using ".." traversal or absolute
+// paths to write outside the extraction directory (CWE-22). containerd's
+// Unpack guards against this internally; since this extractor replaces that
+// backend, it needs the same guarantee for untrusted images and OCI layouts.
+func sanitizeTarPath(dir, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	if target != dir && !strings.HasPrefix(target, dir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("tar entry %q escapes extraction directory", name)
+	}
+	return target, nil
+}
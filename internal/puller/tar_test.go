@@ -0,0 +1,74 @@
+package puller
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUntarRejectsEscapingEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		tarEntry string
+	}{
+		{name: "parent directory traversal", tarEntry: "../../escape"},
+		{name: "nested parent directory traversal", tarEntry: "a/../../../escape"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			if err := tw.WriteHeader(&tar.Header{
+				Name:     tt.tarEntry,
+				Typeflag: tar.TypeReg,
+				Mode:     0644,
+				Size:     0,
+			}); err != nil {
+				t.Fatalf("write tar header: %v", err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatalf("close tar writer: %v", err)
+			}
+
+			dir := t.TempDir()
+			err := untar(&buf, dir)
+			if err == nil {
+				t.Fatalf("untar(%q) succeeded, want error", tt.tarEntry)
+			}
+		})
+	}
+}
+
+func TestUntarExtractsWellFormedEntries(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("hello")
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "manifests/bundle.yaml",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(content)),
+	}); err != nil {
+		t.Fatalf("write tar header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("write tar content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := untar(&buf, dir); err != nil {
+		t.Fatalf("untar: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "manifests", "bundle.yaml"))
+	if err != nil {
+		t.Fatalf("read extracted file: %v", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("extracted content = %q, want %q", got, content)
+	}
+}
@@ -1,28 +1,63 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/operator-framework/operator-registry/pkg/image"
-	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
 	"github.com/operator-framework/operator-registry/pkg/registry"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/sets"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
 
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/operator-framework/operator-registry/pkg/model"
+
+	"github.com/joelanford/declcfg-inline-bundles/internal/cache"
+	"github.com/joelanford/declcfg-inline-bundles/internal/diff"
+	"github.com/joelanford/declcfg-inline-bundles/internal/puller"
 )
 
 var nonRetryableRegex = regexp.MustCompile(`(error resolving name)`)
 
+// bundleMediaTypeLabel is the OCI image label that bundle images use to
+// advertise their format. It's the most reliable signal for distinguishing
+// registry+v1 bundles from plain+v0 bundles, and is checked first via
+// puller.LabelPuller when the active backend has access to the image
+// config; backends that don't (e.g. containerd's unpack output has no
+// config attached) fall back to inspecting the unpacked filesystem.
+const bundleMediaTypeLabel = "operators.operatorframework.io.bundle.mediatype.v1"
+
+const (
+	mediaTypeRegistryV1 = "registry+v1"
+	mediaTypePlainV0    = "plain+v0"
+
+	// bundleMediaTypeProperty records which of the above media types a
+	// bundle was inlined from, so that downstream tools know how to render
+	// its olm.bundle.object properties (CSV/CRDs vs. arbitrary manifests).
+	bundleMediaTypeProperty = "olm.bundle.mediatype"
+)
+
 func main() {
 	cmd := newCmd()
 	if err := cmd.Execute(); err != nil {
@@ -31,25 +66,44 @@ func main() {
 }
 
 func newCmd() *cobra.Command {
-	var pruneNonHeadObjects bool
+	var (
+		pruneNonHeadObjects bool
+		pullerName          string
+		platform            string
+		ociLayoutDir        string
+		localBundles        map[string]string
+		iconValidation      string
+		fixIcons            bool
+		dryRun              bool
+		cacheDir            string
+	)
 	cmd := &cobra.Command{
-		Use:  "declcfg-inline-bundles <configsDir> <bundleImage1> <bundleImage2> ... <bundleImageN>",
+		Use:  "declcfg-inline-bundles <configsDir> <bundleImage1> <bundleImage2> ... <bundleImageN> [--local image=path ...]",
 		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			rootDir := args[0]
 			root := os.DirFS(rootDir)
 			bundleImages := sets.NewString(args[1:]...)
 
-			imageRegistry, err := containerdregistry.NewRegistry(containerdregistry.WithLog(noopLogger()))
+			bp, err := newBundlePuller(pullerName, platform, ociLayoutDir)
 			if err != nil {
-				log.Fatalf("Could not create new containerd registry: %v")
+				log.Fatal(err)
 			}
+			bp = puller.WithLocalOverrides(bp, localBundles)
 			defer func() {
-				if err := imageRegistry.Destroy(); err != nil {
-					log.Warnf("Could not destroy containerd registry: %v", err)
+				if err := bp.Close(); err != nil {
+					log.Warnf("Could not close puller: %v", err)
 				}
 			}()
 
+			var bundleCache *cache.Cache
+			if cacheDir != "" {
+				bundleCache, err = cache.New(cacheDir)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
 			eg := errgroup.Group{}
 
 			log.Info("Loading declarative configuration directory")
@@ -67,9 +121,14 @@ func newCmd() *cobra.Command {
 				log.Fatalf("requested images not found: %v", notPresentImages.List())
 			}
 
+			validationOpts, err := parseValidationOptions(iconValidation)
+			if err != nil {
+				log.Fatal(err)
+			}
+
 			nonChannelHeads := sets.NewString()
 			if pruneNonHeadObjects {
-				nonChannelHeads, err = getAllNonChannelHeads(*cfg)
+				nonChannelHeads, err = getAllNonChannelHeads(*cfg, validationOpts)
 				if err != nil {
 					log.Fatal(err)
 				}
@@ -99,36 +158,16 @@ func newCmd() *cobra.Command {
 						if pruneNonHeadObjects && nonChannelHeads.Has(b.Image) {
 							blog.Info("skipping non-channel head")
 						} else if bundleImages.Len() == 0 || bundleImages.Has(b.Image) {
-							imgRef := image.SimpleReference(b.Image)
-
-							if err := retry.OnError(retry.DefaultRetry,
-								func(err error) bool {
-									if nonRetryableRegex.MatchString(err.Error()) {
-										return false
-									}
-									log.Warnf("  Error pulling image: %v. Retrying.", err)
-									return true
-								},
-								func() error { return imageRegistry.Pull(cmd.Context(), imgRef) }); err != nil {
-								return fmt.Errorf("pull image %q: %v", imgRef, err)
-							}
-
-							tmpDir, err := os.MkdirTemp("", "declcfg-inline-bundles-")
+							mediaType, objs, err := resolveBundleObjects(cmd.Context(), bp, bundleCache, b.Image, blog)
 							if err != nil {
-								return err
-							}
-							if err := imageRegistry.Unpack(cmd.Context(), imgRef, tmpDir); err != nil {
-								return err
-							}
-							ii, err := registry.NewImageInput(image.SimpleReference(b.Image), tmpDir)
-							if err != nil {
-								return err
+								return fmt.Errorf("resolve bundle objects for %q: %v", b.Image, err)
 							}
+
 							props := b.Properties[:0]
 							for _, p := range b.Properties {
-								if p.Type != property.TypeBundleObject {
+								if p.Type != property.TypeBundleObject && p.Type != bundleMediaTypeProperty {
 									props = append(props, p)
-								} else {
+								} else if p.Type == property.TypeBundleObject {
 									var obj property.BundleObject
 									if err := json.Unmarshal(p.Value, &obj); err != nil {
 										return err
@@ -140,30 +179,55 @@ func newCmd() *cobra.Command {
 								}
 							}
 
-							for _, obj := range ii.Bundle.Objects {
-								objJson, err := json.Marshal(obj)
-								if err != nil {
-									return err
-								}
-								props = append(props, property.MustBuildBundleObjectData(objJson))
+							for _, obj := range objs {
+								props = append(props, property.MustBuildBundleObjectData(obj))
 							}
+							props = append(props, mustBuildBundleMediaTypeProperty(mediaType))
 							b.Properties = props
 							fcfg.Bundles[i] = b
-							blog.Info("inlined olm.bundle.object properties")
+							blog.WithField("mediaType", mediaType).Info("inlined olm.bundle.object properties")
 						}
 					}
-					f, err := os.OpenFile(filepath.Join(rootDir, path), os.O_RDWR|os.O_TRUNC, 0666)
-					if err != nil {
-						return err
+					if fixIcons {
+						fixed, err := repairIcons(fcfg)
+						if err != nil {
+							return err
+						}
+						for _, pkgName := range fixed {
+							plog.WithField("package", pkgName).Info("repaired icon media type")
+						}
 					}
+					fullPath := filepath.Join(rootDir, path)
+
+					var buf bytes.Buffer
 					if filepath.Ext(path) == ".yaml" {
-						if err := declcfg.WriteYAML(*fcfg, f); err != nil {
+						if err := declcfg.WriteYAML(*fcfg, &buf); err != nil {
 							return err
 						}
 					} else {
-						if err := declcfg.WriteJSON(*fcfg, f); err != nil {
+						if err := declcfg.WriteJSON(*fcfg, &buf); err != nil {
+							return err
+						}
+					}
+
+					if dryRun {
+						orig, err := ioutil.ReadFile(fullPath)
+						if err != nil {
 							return err
 						}
+						if d := diff.Unified(fullPath, fullPath, string(orig), buf.String()); d != "" {
+							fmt.Print(d)
+						}
+						return nil
+					}
+
+					f, err := os.OpenFile(fullPath, os.O_RDWR|os.O_TRUNC, 0666)
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					if _, err := f.Write(buf.Bytes()); err != nil {
+						return err
 					}
 					return nil
 				})
@@ -176,20 +240,348 @@ func newCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVarP(&pruneNonHeadObjects, "prune-non-head-objects", "p", false, "Prune objects for bundles that are not channel heads.")
+	cmd.Flags().StringVar(&pullerName, "puller", "containerd", "Puller backend to use to retrieve bundle images (containerd, ggcr, oci-layout).")
+	cmd.Flags().StringVar(&platform, "platform", "", "Platform to select when pulling a multi-arch bundle image (ggcr puller only), e.g. linux/amd64.")
+	cmd.Flags().StringVar(&ociLayoutDir, "oci-layout-dir", "", "Local OCI image layout directory to read bundle images from (oci-layout puller only).")
+	cmd.Flags().StringToStringVar(&localBundles, "local", nil, "Image=path mappings of bundle images to local bundle directories, to inline bundles that haven't been pushed to a registry.")
+	cmd.Flags().StringVar(&iconValidation, "icon-validation", "off", "Strictness of package icon validation (strict, warn, off). Used when --prune-non-head-objects requires converting the config to a model.")
+	cmd.Flags().BoolVar(&fixIcons, "fix-icons", false, "Detect mismatched package icon media types and rewrite them in place.")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a unified diff of the changes that would be made, instead of writing them to disk.")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Content-addressable cache directory for extracted bundle objects, keyed by image digest. Speeds up re-runs by skipping pulls for cached digests.")
 	return cmd
 }
 
-func noopLogger() *log.Entry {
-	l := log.New()
-	l.Out = ioutil.Discard
-	return log.NewEntry(l)
+// newBundlePuller constructs the BundlePuller backend selected by kind.
+func newBundlePuller(kind, platform, ociLayoutDir string) (puller.BundlePuller, error) {
+	switch kind {
+	case "containerd":
+		return puller.NewContainerd()
+	case "ggcr":
+		var opts []puller.GGCROption
+		if platform != "" {
+			p, err := ggcrv1.ParsePlatform(platform)
+			if err != nil {
+				return nil, fmt.Errorf("parse platform %q: %v", platform, err)
+			}
+			opts = append(opts, puller.WithPlatform(*p))
+		}
+		return puller.NewGGCR(opts...), nil
+	case "oci-layout":
+		if ociLayoutDir == "" {
+			return nil, fmt.Errorf("--oci-layout-dir is required when using the oci-layout puller")
+		}
+		return puller.NewOCILayout(ociLayoutDir), nil
+	default:
+		return nil, fmt.Errorf("unknown puller %q", kind)
+	}
 }
 
-func getAllNonChannelHeads(cfg declcfg.DeclarativeConfig) (sets.String, error) {
+// resolveBundleObjects returns the media type and olm.bundle.object JSON
+// blobs for imageRef, pulling and unpacking it with bp unless a cache entry
+// for its digest already exists.
+func resolveBundleObjects(ctx context.Context, bp puller.BundlePuller, bundleCache *cache.Cache, imageRef string, blog *log.Entry) (string, []json.RawMessage, error) {
+	var digest string
+	if bundleCache != nil {
+		var err error
+		digest, err = resolveDigest(ctx, bp, imageRef)
+		if err != nil {
+			return "", nil, fmt.Errorf("resolve digest: %v", err)
+		}
+		if e, ok, err := bundleCache.Get(digest); err != nil {
+			return "", nil, fmt.Errorf("read cache: %v", err)
+		} else if ok {
+			blog.WithField("digest", digest).Info("using cached bundle objects")
+			return e.MediaType, e.Objects, nil
+		}
+	}
+
+	var bundleFS fs.FS
+	if err := retry.OnError(retry.DefaultRetry,
+		func(err error) bool {
+			if nonRetryableRegex.MatchString(err.Error()) {
+				return false
+			}
+			log.Warnf("  Error pulling image: %v. Retrying.", err)
+			return true
+		},
+		func() error {
+			var err error
+			bundleFS, err = bp.Pull(ctx, imageRef)
+			return err
+		}); err != nil {
+		return "", nil, fmt.Errorf("pull image: %v", err)
+	}
+
+	dirFS, ok := bundleFS.(puller.DirFS)
+	if !ok {
+		return "", nil, fmt.Errorf("puller returned unsupported filesystem")
+	}
+	tmpDir := dirFS.Dir
+
+	mediaType, err := bundleMediaTypeFromLabel(ctx, bp, imageRef)
+	if err != nil {
+		return "", nil, err
+	}
+	if mediaType == "" {
+		mediaType, err = detectBundleMediaType(tmpDir)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var rawObjs []json.RawMessage
+	switch mediaType {
+	case mediaTypePlainV0:
+		objs, err := loadPlainBundleObjects(tmpDir)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, obj := range objs {
+			objJson, err := json.Marshal(obj)
+			if err != nil {
+				return "", nil, err
+			}
+			rawObjs = append(rawObjs, objJson)
+		}
+	default:
+		ii, err := registry.NewImageInput(image.SimpleReference(imageRef), tmpDir)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, obj := range ii.Bundle.Objects {
+			objJson, err := json.Marshal(obj)
+			if err != nil {
+				return "", nil, err
+			}
+			rawObjs = append(rawObjs, objJson)
+		}
+	}
+
+	if bundleCache != nil {
+		if err := bundleCache.Put(digest, cache.Entry{MediaType: mediaType, Objects: rawObjs}); err != nil {
+			return "", nil, fmt.Errorf("write cache: %v", err)
+		}
+	}
+
+	return mediaType, rawObjs, nil
+}
+
+// resolveDigest resolves imageRef to a content digest, preferring bp's own
+// puller.DigestPuller capability when it has one (oci-layout, and local
+// paths layered in via --local) so that offline-only backends never touch
+// the network just to populate the cache key. Only when bp can't resolve a
+// digest itself does this fall back to a registry descriptor lookup,
+// rather than trusting a (mutable) tag; references that aren't resolvable
+// against a registry at all fall back further to a digest derived from the
+// reference string itself.
+func resolveDigest(ctx context.Context, bp puller.BundlePuller, imageRef string) (string, error) {
+	if dp, ok := bp.(puller.DigestPuller); ok {
+		digest, err := dp.Digest(ctx, imageRef)
+		if err == nil {
+			return digest, nil
+		}
+		if !errors.Is(err, puller.ErrDigestUnsupported) {
+			return "", err
+		}
+	}
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		sum := sha256.Sum256([]byte(imageRef))
+		return "sha256:" + hex.EncodeToString(sum[:]), nil
+	}
+	desc, err := remote.Get(ref, remote.WithContext(ctx))
+	if err != nil {
+		log.Warnf("Could not resolve registry descriptor for %q, falling back to a reference-derived cache key: %v", imageRef, err)
+		sum := sha256.Sum256([]byte(imageRef))
+		return "sha256:" + hex.EncodeToString(sum[:]), nil
+	}
+	return desc.Digest.String(), nil
+}
+
+// bundleMediaTypeFromLabel returns the bundleMediaTypeLabel value from
+// imageRef's image config, using bp's puller.LabelPuller capability when
+// the active backend has one. It returns "" (not an error) when the
+// backend can't provide labels, or the label isn't set, so callers fall
+// back to detectBundleMediaType.
+func bundleMediaTypeFromLabel(ctx context.Context, bp puller.BundlePuller, imageRef string) (string, error) {
+	lp, ok := bp.(puller.LabelPuller)
+	if !ok {
+		return "", nil
+	}
+	labels, err := lp.Labels(ctx, imageRef)
+	if err != nil {
+		return "", fmt.Errorf("read image labels for %q: %v", imageRef, err)
+	}
+	return labels[bundleMediaTypeLabel], nil
+}
+
+// detectBundleMediaType determines whether an unpacked bundle image is a
+// registry+v1 bundle (OLM CSV/CRD manifests) or a plain+v0 bundle (arbitrary
+// Kubernetes YAML under manifests/). It does this by walking the unpacked
+// filesystem looking for a ClusterServiceVersion manifest, since image config
+// labels aren't available once the image has already been unpacked.
+func detectBundleMediaType(dir string) (string, error) {
+	manifestsDir := filepath.Join(dir, "manifests")
+	entries, err := ioutil.ReadDir(manifestsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return mediaTypePlainV0, nil
+		}
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(manifestsDir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		docs, err := splitYAMLDocs(data)
+		if err != nil {
+			return "", err
+		}
+		for _, doc := range docs {
+			var tm struct {
+				Kind string `json:"kind"`
+			}
+			if err := yaml.Unmarshal(doc, &tm); err != nil {
+				continue
+			}
+			if tm.Kind == "ClusterServiceVersion" || tm.Kind == "CustomResourceDefinition" {
+				return mediaTypeRegistryV1, nil
+			}
+		}
+	}
+	return mediaTypePlainV0, nil
+}
+
+// loadPlainBundleObjects reads every YAML file under dir/manifests, splits
+// multi-document streams, and decodes each document into an
+// unstructured.Unstructured so it can be inlined as an olm.bundle.object
+// property.
+func loadPlainBundleObjects(dir string) ([]interface{}, error) {
+	manifestsDir := filepath.Join(dir, "manifests")
+	entries, err := ioutil.ReadDir(manifestsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var objs []interface{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(e.Name())
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(manifestsDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		docs, err := splitYAMLDocs(data)
+		if err != nil {
+			return nil, fmt.Errorf("split yaml docs in %q: %v", e.Name(), err)
+		}
+		for _, doc := range docs {
+			var m map[string]interface{}
+			if err := yaml.Unmarshal(doc, &m); err != nil {
+				return nil, fmt.Errorf("unmarshal yaml doc in %q: %v", e.Name(), err)
+			}
+			if len(m) == 0 {
+				continue
+			}
+			objs = append(objs, &unstructured.Unstructured{Object: m})
+		}
+	}
+	return objs, nil
+}
+
+// splitYAMLDocs splits a multi-document YAML stream into its individual
+// documents.
+func splitYAMLDocs(data []byte) ([][]byte, error) {
+	var docs [][]byte
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func mustBuildBundleMediaTypeProperty(mediaType string) property.Property {
+	value, err := json.Marshal(struct {
+		MediaType string `json:"mediaType"`
+	}{MediaType: mediaType})
+	if err != nil {
+		panic(err)
+	}
+	return property.Property{
+		Type:  bundleMediaTypeProperty,
+		Value: value,
+	}
+}
+
+// parseValidationOptions translates the --icon-validation flag value into a
+// model.ValidationOptions.
+func parseValidationOptions(iconValidation string) (model.ValidationOptions, error) {
+	opts := model.ValidationOptions{Log: log.Warnf}
+	switch iconValidation {
+	case "strict":
+		opts.IconValidation = model.IconValidationStrict
+	case "warn":
+		opts.IconValidation = model.IconValidationWarn
+	case "off":
+		opts.IconValidation = model.IconValidationOff
+	default:
+		return opts, fmt.Errorf("invalid --icon-validation %q: must be one of strict, warn, off", iconValidation)
+	}
+	return opts, nil
+}
+
+// repairIcons detects the actual media type of every package icon in cfg
+// and overwrites mismatched MediaType fields, returning the names of the
+// packages that were changed. The detection itself is delegated to
+// model.Icon.Repair so the CLI and the model package can't drift apart on
+// what counts as a mismatch.
+func repairIcons(cfg *declcfg.DeclarativeConfig) ([]string, error) {
+	var fixed []string
+	for i, pkg := range cfg.Packages {
+		if pkg.Icon == nil || len(pkg.Icon.Data) == 0 {
+			continue
+		}
+		mi := model.Icon{Data: pkg.Icon.Data, MediaType: pkg.Icon.MediaType}
+		changed, err := mi.Repair()
+		if err != nil {
+			return nil, fmt.Errorf("detect icon media type for package %q: %v", pkg.Name, err)
+		}
+		if changed {
+			cfg.Packages[i].Icon.MediaType = mi.MediaType
+			fixed = append(fixed, pkg.Name)
+		}
+	}
+	return fixed, nil
+}
+
+func getAllNonChannelHeads(cfg declcfg.DeclarativeConfig, opts model.ValidationOptions) (sets.String, error) {
 	m, err := declcfg.ConvertToModel(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("convert index to model: %v", err)
 	}
+	if err := m.ValidateWithOptions(opts); err != nil {
+		return nil, fmt.Errorf("validate model: %v", err)
+	}
 
 	nonChannelHeads := sets.NewString()
 	for _, pkg := range m {
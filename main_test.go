@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDetectBundleMediaType(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{
+			name: "plain bundle with arbitrary Kubernetes manifests",
+			dir:  "testdata/plain-bundle",
+			want: mediaTypePlainV0,
+		},
+		{
+			name: "registry+v1 bundle with a CSV",
+			dir:  "testdata/registry-bundle",
+			want: mediaTypeRegistryV1,
+		},
+		{
+			name: "no manifests directory at all",
+			dir:  t.TempDir(),
+			want: mediaTypePlainV0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectBundleMediaType(tt.dir)
+			if err != nil {
+				t.Fatalf("detectBundleMediaType(%q): %v", tt.dir, err)
+			}
+			if got != tt.want {
+				t.Errorf("detectBundleMediaType(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPlainBundleObjects(t *testing.T) {
+	objs, err := loadPlainBundleObjects("testdata/plain-bundle")
+	if err != nil {
+		t.Fatalf("loadPlainBundleObjects: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects, want 2", len(objs))
+	}
+	var kinds []string
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			t.Fatalf("object is %T, want *unstructured.Unstructured", obj)
+		}
+		kinds = append(kinds, u.GetKind())
+	}
+	want := []string{"ConfigMap", "Service"}
+	for i, k := range want {
+		if kinds[i] != k {
+			t.Errorf("object %d kind = %q, want %q", i, kinds[i], k)
+		}
+	}
+}
+
+func TestLoadPlainBundleObjectsNoManifestsDir(t *testing.T) {
+	objs, err := loadPlainBundleObjects(t.TempDir())
+	if err != nil {
+		t.Fatalf("loadPlainBundleObjects: %v", err)
+	}
+	if objs != nil {
+		t.Errorf("got %v, want nil", objs)
+	}
+}
+
+func TestSplitYAMLDocs(t *testing.T) {
+	data := []byte("a: 1\n---\nb: 2\n---\n---\n")
+	docs, err := splitYAMLDocs(data)
+	if err != nil {
+		t.Fatalf("splitYAMLDocs: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d docs, want 2", len(docs))
+	}
+}
@@ -21,12 +21,41 @@ func init() {
 
 type Model map[string]*Package
 
+// IconValidation controls how strictly Model.Validate checks package icons.
+type IconValidation int
+
+const (
+	// IconValidationStrict fails validation if an icon's data does not
+	// match its declared media type.
+	IconValidationStrict IconValidation = iota
+	// IconValidationWarn logs a warning (via ValidationOptions.Log, if set)
+	// instead of failing validation when an icon's data does not match its
+	// declared media type.
+	IconValidationWarn
+	// IconValidationOff skips icon data/media type checks entirely.
+	IconValidationOff
+)
+
+// ValidationOptions configures the strictness of Model.Validate. Production
+// catalogs are known to contain packages with mismatched icon metadata, so
+// the stricter icon checks are opt-in rather than unconditional.
+type ValidationOptions struct {
+	IconValidation IconValidation
+	// Log receives warnings produced under IconValidationWarn. If nil,
+	// warnings are discarded.
+	Log func(format string, args ...interface{})
+}
+
 func (m Model) Validate() error {
+	return m.ValidateWithOptions(ValidationOptions{IconValidation: IconValidationOff})
+}
+
+func (m Model) ValidateWithOptions(opts ValidationOptions) error {
 	for name, pkg := range m {
 		if name != pkg.Name {
 			return fmt.Errorf("package key %q does not match package name %q", name, pkg.Name)
 		}
-		if err := pkg.Validate(); err != nil {
+		if err := pkg.validate(opts); err != nil {
 			return fmt.Errorf("invalid package %q: %v", pkg.Name, err)
 		}
 	}
@@ -42,11 +71,15 @@ type Package struct {
 }
 
 func (m *Package) Validate() error {
+	return m.validate(ValidationOptions{IconValidation: IconValidationOff})
+}
+
+func (m *Package) validate(opts ValidationOptions) error {
 	if m.Name == "" {
 		return errors.New("package name must not be empty")
 	}
 
-	if err := m.Icon.Validate(); err != nil {
+	if err := m.Icon.ValidateWithOptions(opts); err != nil {
 		return fmt.Errorf("invalid icon: %v", err)
 	}
 
@@ -85,22 +118,33 @@ type Icon struct {
 	MediaType string
 }
 
+// Validate checks i using the default (off) icon validation strictness. It
+// exists for callers that don't need to opt into the stricter checks.
 func (i *Icon) Validate() error {
+	return i.ValidateWithOptions(ValidationOptions{IconValidation: IconValidationOff})
+}
+
+func (i *Icon) ValidateWithOptions(opts ValidationOptions) error {
 	if i == nil {
 		return nil
 	}
-	// TODO(joelanford): Should we check that data and mediatype are set,
-	//   and detect the media type of the data and compare it to the
-	//   mediatype listed in the icon field? Currently, some production
-	//   index databases are failing these tests, so leaving this
-	//   commented out for now.
-	//if len(i.Data) == 0 {
-	//	return errors.New("icon data must be set if icon is defined")
-	//}
-	//if len(i.MediaType) == 0 {
-	//	return errors.New("icon mediatype must be set if icon is defined")
-	//}
-	//return i.validateData()
+	if opts.IconValidation == IconValidationOff {
+		return nil
+	}
+	if len(i.Data) == 0 {
+		return errors.New("icon data must be set if icon is defined")
+	}
+	if len(i.MediaType) == 0 {
+		return errors.New("icon mediatype must be set if icon is defined")
+	}
+	err := i.validateData()
+	if err == nil || opts.IconValidation == IconValidationStrict {
+		return err
+	}
+	// IconValidationWarn: surface the problem without failing validation.
+	if opts.Log != nil {
+		opts.Log("icon validation: %v", err)
+	}
 	return nil
 }
 
@@ -108,16 +152,43 @@ func (i *Icon) validateData() error {
 	if !filetype.IsImage(i.Data) {
 		return errors.New("icon data is not an image")
 	}
-	t, err := filetype.Match(i.Data)
+	detected, err := i.DetectMediaType()
 	if err != nil {
 		return err
 	}
-	if t.MIME.Value != i.MediaType {
-		return fmt.Errorf("icon media type %q does not match detected media type %q", i.MediaType, t.MIME.Value)
+	if detected != i.MediaType {
+		return fmt.Errorf("icon media type %q does not match detected media type %q", i.MediaType, detected)
 	}
 	return nil
 }
 
+// DetectMediaType inspects i.Data and returns its actual media type, using
+// filetype matchers (including the SVG matcher registered in init()).
+func (i *Icon) DetectMediaType() (string, error) {
+	t, err := filetype.Match(i.Data)
+	if err != nil {
+		return "", err
+	}
+	return t.MIME.Value, nil
+}
+
+// Repair overwrites i.MediaType with the media type detected from i.Data if
+// they disagree, reporting whether it made a change.
+func (i *Icon) Repair() (bool, error) {
+	if i == nil || len(i.Data) == 0 {
+		return false, nil
+	}
+	detected, err := i.DetectMediaType()
+	if err != nil {
+		return false, err
+	}
+	if detected == i.MediaType {
+		return false, nil
+	}
+	i.MediaType = detected
+	return true, nil
+}
+
 type Channel struct {
 	Package *Package
 	Name    string
@@ -125,8 +196,9 @@ type Channel struct {
 }
 
 // TODO(joelanford): This function determines the channel head by finding the bundle that has 0
-//   incoming edges, based on replaces and skips. It also expects to find exactly one such bundle.
-//   Is this the correct algorithm?
+//
+//	incoming edges, based on replaces and skips. It also expects to find exactly one such bundle.
+//	Is this the correct algorithm?
 func (c Channel) Head() (*Bundle, error) {
 	incoming := map[string]int{}
 	for _, b := range c.Bundles {
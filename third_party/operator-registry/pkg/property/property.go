@@ -0,0 +1,86 @@
+package property
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Well-known property types used by the index database.
+const (
+	TypePackage = "olm.package"
+	TypeGVK     = "olm.gvk"
+)
+
+// Property is a generic key/value pair attached to a bundle, channel, or
+// package in the index database.
+type Property struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Package is the parsed value of an olm.package property.
+type Package struct {
+	PackageName string `json:"packageName"`
+	Version     string `json:"version"`
+}
+
+// GVK is the parsed value of an olm.gvk property.
+type GVK struct {
+	Group   string `json:"group"`
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+}
+
+// Properties is the result of parsing a bundle's properties into their
+// well-known types. Property types this package doesn't recognize are
+// ignored rather than rejected, so callers aren't broken by unrelated
+// property types appearing on a bundle.
+type Properties struct {
+	Packages []Package
+	GVKs     []GVK
+}
+
+// Parse decodes props into a Properties.
+func Parse(props []Property) (*Properties, error) {
+	var parsed Properties
+	for _, p := range props {
+		switch p.Type {
+		case TypePackage:
+			var pkg Package
+			if err := json.Unmarshal(p.Value, &pkg); err != nil {
+				return nil, fmt.Errorf("parse property %q: %v", p.Type, err)
+			}
+			parsed.Packages = append(parsed.Packages, pkg)
+		case TypeGVK:
+			var gvk GVK
+			if err := json.Unmarshal(p.Value, &gvk); err != nil {
+				return nil, fmt.Errorf("parse property %q: %v", p.Type, err)
+			}
+			parsed.GVKs = append(parsed.GVKs, gvk)
+		}
+	}
+	return &parsed, nil
+}
+
+// ValidateBackCompat checks the invariant required to serve a bundle
+// through the legacy (non-declarative-config) index database API: exactly
+// one olm.package property.
+func ValidateBackCompat(p Properties) error {
+	if len(p.Packages) != 1 {
+		return fmt.Errorf("expected exactly 1 property of type %q, found %d", TypePackage, len(p.Packages))
+	}
+	return nil
+}
+
+// Build re-encodes p.Value in its canonical JSON form.
+func Build(p *Property) (*Property, error) {
+	var v interface{}
+	if err := json.Unmarshal(p.Value, &v); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Property{Type: p.Type, Value: value}, nil
+}